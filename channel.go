@@ -0,0 +1,315 @@
+package pusher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Event is a single Pusher protocol frame, sent or received over the
+// websocket connection.
+type Event struct {
+	Event   string          `json:"event"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Channel string          `json:"channel,omitempty"`
+}
+
+// Channel represents a subscription to a Pusher channel, as returned by
+// Client.Subscribe/SubscribeContext.
+type Channel interface {
+	// Name returns the channel's name.
+	Name() string
+	// Subscribe (re)sends the pusher:subscribe request for this channel,
+	// authenticating against Client.AuthURL first if it's a private or
+	// presence channel. Client.Subscribe already calls this once; call it
+	// again to retry a failed subscription.
+	Subscribe(opts ...SubscribeOption) error
+	// Unsubscribe sends the pusher:unsubscribe request for this channel.
+	Unsubscribe() error
+	// Bind returns a channel to which the data of every event named event,
+	// received on this channel, is sent.
+	Bind(event string) chan json.RawMessage
+	// Unbind removes bindings registered by Bind. If chans are passed, only
+	// those bindings are removed; otherwise all bindings for event are.
+	Unbind(event string, chans ...chan json.RawMessage)
+	// Trigger publishes a client event on the channel. See the Trigger
+	// doc comment in client_events.go for the client-event rules it enforces.
+	Trigger(eventName string, data interface{}) error
+}
+
+// PresenceChannel is a Channel that additionally tracks the set of members
+// present on the channel, as returned by Client.SubscribePresence.
+type PresenceChannel interface {
+	Channel
+	// Members returns a snapshot of the channel's current members, keyed by
+	// user ID.
+	Members() map[string]json.RawMessage
+}
+
+// internalChannel is the subset of Channel (plus the protocol-dispatch hook
+// handleEvent) that Client itself needs; Client.subscribedChannels stores
+// values of this type so listen() can hand off incoming events without a
+// type assertion back to a concrete channel type.
+type internalChannel interface {
+	Channel
+	handleEvent(event string, data json.RawMessage)
+}
+
+// subscribeParams holds the state a SubscribeOption may adjust before a
+// subscribe request is sent.
+type subscribeParams struct{}
+
+// SubscribeOption customizes a Subscribe/SubscribeContext/SubscribePresence
+// call. There are no exported constructors yet; the type exists so future
+// options (e.g. a presence channel_data override) can be added without
+// another breaking signature change.
+type SubscribeOption func(*subscribeParams)
+
+type boundDataChans map[chan json.RawMessage]struct{}
+
+// channel is the base implementation of Channel, used directly for public
+// channels and embedded by privateChannel/presenceChannel.
+type channel struct {
+	name   string
+	client *Client
+
+	mutex       sync.RWMutex
+	boundEvents map[string]boundDataChans
+}
+
+func (ch *channel) Name() string {
+	return ch.name
+}
+
+func (ch *channel) Subscribe(opts ...SubscribeOption) error {
+	return ch.sendSubscribe(subscribeData{Channel: ch.name})
+}
+
+// subscribeData is the data payload of a pusher:subscribe event.
+type subscribeData struct {
+	Channel     string `json:"channel"`
+	Auth        string `json:"auth,omitempty"`
+	ChannelData string `json:"channel_data,omitempty"`
+}
+
+func (ch *channel) sendSubscribe(data subscribeData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return ch.client.Transport.WriteJSON(Event{Event: pusherSubscribe, Data: payload})
+}
+
+func (ch *channel) Unsubscribe() error {
+	payload, err := json.Marshal(struct {
+		Channel string `json:"channel"`
+	}{Channel: ch.name})
+	if err != nil {
+		return err
+	}
+	return ch.client.Transport.WriteJSON(Event{Event: pusherUnsubscribe, Data: payload})
+}
+
+func (ch *channel) Bind(event string) chan json.RawMessage {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	boundChan := make(chan json.RawMessage)
+	if ch.boundEvents[event] == nil {
+		ch.boundEvents[event] = boundDataChans{}
+	}
+	ch.boundEvents[event][boundChan] = struct{}{}
+
+	return boundChan
+}
+
+func (ch *channel) Unbind(event string, chans ...chan json.RawMessage) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+
+	if len(chans) == 0 {
+		delete(ch.boundEvents, event)
+		return
+	}
+
+	eventBoundChans := ch.boundEvents[event]
+	for _, boundChan := range chans {
+		delete(eventBoundChans, boundChan)
+	}
+}
+
+// handleEvent dispatches event data received on this channel to anything
+// bound via Bind, mirroring Client.listen's handling of its own boundEvents.
+func (ch *channel) handleEvent(event string, data json.RawMessage) {
+	switch event {
+	case pusherSubSucceeded, pusherInternalSubSucceeded:
+		return
+	}
+
+	ch.mutex.RLock()
+	boundChans := make([]chan json.RawMessage, 0, len(ch.boundEvents[event]))
+	for boundChan := range ch.boundEvents[event] {
+		boundChans = append(boundChans, boundChan)
+	}
+	ch.mutex.RUnlock()
+
+	for _, boundChan := range boundChans {
+		go func(boundChan chan json.RawMessage, data json.RawMessage) {
+			boundChan <- data
+		}(boundChan, data)
+	}
+}
+
+// privateChannel is a Channel requiring authentication against
+// Client.AuthURL before Pusher will accept the subscription.
+type privateChannel struct {
+	*channel
+}
+
+func (ch *privateChannel) Subscribe(opts ...SubscribeOption) error {
+	auth, err := ch.client.authenticate(ch.name)
+	if err != nil {
+		return err
+	}
+	return ch.sendSubscribe(subscribeData{Channel: ch.name, Auth: auth.Auth})
+}
+
+// presenceChannel is a privateChannel that additionally tracks channel
+// members via pusher_internal:member_added/member_removed events.
+type presenceChannel struct {
+	*privateChannel
+
+	membersMu sync.RWMutex
+	members   map[string]json.RawMessage
+}
+
+func newPresenceChannel(base *channel) *presenceChannel {
+	return &presenceChannel{
+		privateChannel: &privateChannel{base},
+		members:        map[string]json.RawMessage{},
+	}
+}
+
+func (ch *presenceChannel) Subscribe(opts ...SubscribeOption) error {
+	auth, err := ch.client.authenticate(ch.name)
+	if err != nil {
+		return err
+	}
+	return ch.sendSubscribe(subscribeData{Channel: ch.name, Auth: auth.Auth, ChannelData: auth.ChannelData})
+}
+
+func (ch *presenceChannel) Members() map[string]json.RawMessage {
+	ch.membersMu.RLock()
+	defer ch.membersMu.RUnlock()
+
+	members := make(map[string]json.RawMessage, len(ch.members))
+	for id, info := range ch.members {
+		members[id] = info
+	}
+	return members
+}
+
+type presenceMemberData struct {
+	UserID   string          `json:"user_id"`
+	UserInfo json.RawMessage `json:"user_info,omitempty"`
+}
+
+type presenceSubscriptionData struct {
+	Presence struct {
+		IDs  []string                   `json:"ids"`
+		Hash map[string]json.RawMessage `json:"hash"`
+	} `json:"presence"`
+}
+
+func (ch *presenceChannel) handleEvent(event string, data json.RawMessage) {
+	switch event {
+	case pusherInternalSubSucceeded:
+		var sub presenceSubscriptionData
+		if err := UnmarshalDataString(data, &sub); err == nil {
+			ch.membersMu.Lock()
+			ch.members = make(map[string]json.RawMessage, len(sub.Presence.IDs))
+			for id, info := range sub.Presence.Hash {
+				ch.members[id] = info
+			}
+			ch.membersMu.Unlock()
+		}
+		return
+	case pusherInternalMemberAdded:
+		var member presenceMemberData
+		if err := UnmarshalDataString(data, &member); err == nil {
+			ch.membersMu.Lock()
+			ch.members[member.UserID] = member.UserInfo
+			ch.membersMu.Unlock()
+		}
+		return
+	case pusherInternalMemberRemoved:
+		var member presenceMemberData
+		if err := UnmarshalDataString(data, &member); err == nil {
+			ch.membersMu.Lock()
+			delete(ch.members, member.UserID)
+			ch.membersMu.Unlock()
+		}
+		return
+	}
+	ch.channel.handleEvent(event, data)
+}
+
+// authResponse is the JSON body Client.AuthURL is expected to return for a
+// private/presence channel authentication request. ChannelData is left
+// double-encoded (a JSON string), matching how Pusher sends it back on the
+// wire in a pusher:subscribe request.
+type authResponse struct {
+	Auth        string `json:"auth"`
+	ChannelData string `json:"channel_data,omitempty"`
+}
+
+// authenticate calls Client.AuthURL to authenticate a private or presence
+// channel subscription for channelName, per
+// https://pusher.com/docs/channels/library_auth_reference/auth-signatures/.
+func (c *Client) authenticate(channelName string) (authResponse, error) {
+	c.mutex.RLock()
+	authURL := c.AuthURL
+	socketID := c.socketID
+	authParams := c.AuthParams
+	authHeaders := c.AuthHeaders
+	c.mutex.RUnlock()
+
+	if authURL == "" {
+		return authResponse{}, fmt.Errorf("pusher: AuthURL is required to subscribe to %s", channelName)
+	}
+
+	form := url.Values{}
+	for k, v := range authParams {
+		form[k] = v
+	}
+	form.Set("socket_id", socketID)
+	form.Set("channel_name", channelName)
+
+	req, err := http.NewRequest(http.MethodPost, authURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return authResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for k, v := range authHeaders {
+		req.Header[k] = v
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return authResponse{}, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return authResponse{}, fmt.Errorf("%w: auth endpoint returned status %d", ErrAuthFailed, resp.StatusCode)
+	}
+
+	var auth authResponse
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return authResponse{}, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+	}
+	return auth, nil
+}
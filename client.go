@@ -1,6 +1,7 @@
 package pusher
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,14 +9,9 @@ import (
 	"strings"
 	"sync"
 	"time"
-
-	"golang.org/x/net/websocket"
 )
 
 const (
-	pingPayload = `{"event":"pusher:ping","data":"{}"}`
-	pongPayload = `{"event":"pusher:pong","data":"{}"}`
-
 	// api recommended value for pong timeout
 	pongTimeout = 30 * time.Second
 
@@ -64,15 +60,53 @@ type Client struct {
 	// Additional HTTP headers to be sent in an authentication request.
 	AuthHeaders http.Header
 
+	// Transport is the WebSocket implementation used to talk to Pusher. If
+	// nil, Connect and ConnectContext lazily default it to a Transport backed
+	// by github.com/gorilla/websocket. Set it before connecting to inject a
+	// custom dialer, proxy, or TLS configuration.
+	Transport Transport
+	// ConnectHeaders are additional HTTP headers sent during the WebSocket
+	// handshake, e.g. to satisfy a proxy in front of Pusher.
+	ConnectHeaders http.Header
+
 	// If provided, errors that occur while receiving messages and errors emitted
 	// by Pusher will be sent to this channel.
 	Errors chan error
 
+	// If provided, connection lifecycle transitions (connecting, connected,
+	// disconnected, reconnecting, failed) are sent to this channel as the
+	// client reconnects. See ConnectionState.
+	StateChanges chan ConnectionState
+
+	// MaxReconnectAttempts caps how many times the client will try to redial
+	// Pusher after an unexpected disconnect before giving up and sending
+	// StateFailed. A value of 0 means retry forever.
+	MaxReconnectAttempts int
+	// ReconnectWait is the base delay used by the full-jitter exponential
+	// backoff between reconnect attempts. Defaults to 1s if unset.
+	ReconnectWait time.Duration
+	// MaxReconnectWait caps the exponential growth of ReconnectWait. Defaults
+	// to 30s if unset.
+	MaxReconnectWait time.Duration
+	// ReconnectJitter adds additional random delay on top of the backoff, to
+	// avoid thundering-herd reconnects across many clients. Defaults to 0.
+	ReconnectJitter time.Duration
+
+	// PongTimeout is how long the client waits for a pusher:pong after
+	// sending pusher:ping before treating the socket as dead. Defaults to
+	// pongTimeout (the API-recommended value) if unset.
+	PongTimeout time.Duration
+
 	closes        []chan error
 	notifyMutex   sync.RWMutex
 	disconnectErr error
 
 	socketID string
+	appKey   string
+
+	eventRateMu        sync.Mutex
+	eventTokens        float64
+	eventTokensUpdated time.Time
 
 	// Configureable timeout that will override the value
 	// provided by the API on connect if configured
@@ -82,15 +116,27 @@ type Client struct {
 	// TODO: implement timeout logic
 	// pongTimeout time.Duration
 
-	ws                 *websocket.Conn
-	connected          bool
+	connected bool
+	// generation increments on every successful Connect/reconnect dial. It
+	// lets a listen/heartbeat goroutine from a prior connection attempt,
+	// which shares c.Transport, c.pongTimer, and c.activityTimer with
+	// whatever dial comes after it, recognize it has been superseded and
+	// stop touching that shared state instead of racing the new connection.
+	generation         uint64
+	manualDisconnect   bool
 	activityTimer      *time.Timer
 	activityTimerReset chan struct{}
+	pongTimer          *time.Timer
 	boundEvents        map[string]boundEventChans
-	// TODO: implement global bindings
-	// globalBindings     boundEventChans
+	globalBindings     boundEventChans
 	subscribedChannels subscribedChannels
 
+	eventHandlers      map[string][]func(Event)
+	connectedHandlers  []func()
+	disconnectHandlers []func(error)
+	errorHandlers      []func(error)
+	dispatchQueue      chan func()
+
 	mutex sync.RWMutex
 
 	// used for testing
@@ -147,18 +193,43 @@ func (c *Client) NotifyClose(ch chan error) chan error {
 
 // Connect establishes a connection to the Pusher app specified by appKey.
 func (c *Client) Connect(appKey string) error {
+	return c.ConnectContext(context.Background(), appKey)
+}
+
+// ConnectContext establishes a connection to the Pusher app specified by
+// appKey, aborting if ctx is canceled before the handshake with Pusher
+// completes. Canceling ctx after a successful connect has no effect; use
+// Disconnect to close an established connection.
+func (c *Client) ConnectContext(ctx context.Context, appKey string) error {
+	// fireConnected takes c.mutex itself, so it runs from this deferred call,
+	// which executes after the deferred Unlock registered below.
+	established := false
+	defer func() {
+		if established {
+			c.fireConnected()
+		}
+	}()
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	var err error
-	c.ws, err = websocket.Dial(c.generateConnURL(appKey), "", localOrigin)
-	if err != nil {
+	if c.Transport == nil {
+		c.Transport = &gorillaTransport{}
+	}
+
+	header := http.Header{}
+	for k, v := range c.ConnectHeaders {
+		header[k] = v
+	}
+	if header.Get("Origin") == "" {
+		header.Set("Origin", localOrigin)
+	}
+
+	if err := c.Transport.DialContext(ctx, c.generateConnURL(appKey), header); err != nil {
 		return err
 	}
 
 	var event Event
-	err = websocket.JSON.Receive(c.ws, &event)
-	if err != nil {
+	if err := c.Transport.ReadJSON(&event); err != nil {
 		return err
 	}
 
@@ -167,11 +238,12 @@ func (c *Client) Connect(appKey string) error {
 		return extractEventError(event)
 	case pusherConnEstablished:
 		var connData connectionData
-		err = UnmarshalDataString(event.Data, &connData)
-		if err != nil {
+		if err := UnmarshalDataString(event.Data, &connData); err != nil {
 			return err
 		}
 		c.connected = true
+		c.manualDisconnect = false
+		c.appKey = appKey
 		c.socketID = connData.SocketID
 		if c.ActivityTimeout > 0 {
 			c._activityTimeout = c.ActivityTimeout
@@ -180,12 +252,22 @@ func (c *Client) Connect(appKey string) error {
 		}
 		c.activityTimer = time.NewTimer(c._activityTimeout)
 		c.activityTimerReset = make(chan struct{}, 1)
-		c.boundEvents = map[string]boundEventChans{}
-		c.subscribedChannels = subscribedChannels{}
+		if c.boundEvents == nil {
+			c.boundEvents = map[string]boundEventChans{}
+		}
+		if c.subscribedChannels == nil {
+			c.subscribedChannels = subscribedChannels{}
+		}
 		c.disconnectErr = nil
+		c.generation++
+		gen := c.generation
+		c.ensureDispatchLocked()
+
+		go c.heartbeat(gen)
+		go c.listen(gen)
 
-		go c.heartbeat()
-		go c.listen()
+		c.publishState(StateConnected)
+		established = true
 
 		return nil
 	default:
@@ -200,6 +282,18 @@ func (c *Client) isConnected() bool {
 	return c.connected
 }
 
+// isCurrentGen reports whether gen is still the active connection's
+// generation, i.e. whether the caller (a listen/heartbeat/onPongTimeout
+// invocation started by a particular Connect/reconnect) is still allowed to
+// act on the shared Transport/timer state rather than having been superseded
+// by a later reconnect.
+func (c *Client) isCurrentGen(gen uint64) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.connected && c.generation == gen
+}
+
 func (c *Client) resetActivityTimer() {
 	select {
 	case c.activityTimerReset <- struct{}{}:
@@ -209,8 +303,10 @@ func (c *Client) resetActivityTimer() {
 	}
 }
 
-func (c *Client) heartbeat() {
-	for c.isConnected() {
+// heartbeat runs the activity-timer/ping loop for the connection identified
+// by gen, exiting as soon as that connection is no longer the current one.
+func (c *Client) heartbeat(gen uint64) {
+	for c.isCurrentGen(gen) {
 		select {
 		case <-c.activityTimerReset:
 			if !c.activityTimer.Stop() {
@@ -224,57 +320,186 @@ func (c *Client) heartbeat() {
 			c.activityTimer.Reset(c._activityTimeout)
 
 		case <-c.activityTimer.C:
-			websocket.Message.Send(c.ws, pingPayload)
-			// TODO: implement timeout/reconnect logic
+			// A heartbeat parked in this select when its generation was torn
+			// down can still receive a tick its timer delivered before
+			// disconnectContext stopped it; re-check right before acting, not
+			// just at the loop's for condition, so it can't ping on a
+			// since-replaced Transport.
+			if !c.isCurrentGen(gen) {
+				return
+			}
+			c.Transport.WriteJSON(Event{Event: pusherPing, Data: json.RawMessage(`"{}"`)})
+			c.armPongTimeout(gen)
 		}
 	}
 }
 
+// armPongTimeout starts (or restarts) the timer that fires onPongTimeout for
+// generation gen if no pusher:pong, or any other inbound frame proving the
+// socket is alive, arrives before it elapses. It's a no-op if gen has since
+// been superseded, so a stale heartbeat can't clobber the pong timer armed
+// for the live generation.
+func (c *Client) armPongTimeout(gen uint64) {
+	timeout := c.PongTimeout
+	if timeout <= 0 {
+		timeout = pongTimeout
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.connected || c.generation != gen {
+		return
+	}
+	if c.pongTimer != nil {
+		c.pongTimer.Stop()
+	}
+	c.pongTimer = time.AfterFunc(timeout, func() { c.onPongTimeout(gen) })
+}
+
+// cancelPongTimeout stops a pending pong timeout timer, if any. It is called
+// whenever an inbound frame proves the connection is still alive.
+func (c *Client) cancelPongTimeout() {
+	c.mutex.Lock()
+	timer := c.pongTimer
+	c.pongTimer = nil
+	c.mutex.Unlock()
+
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// onPongTimeout treats the socket as dead after the server failed to respond
+// to a pusher:ping within PongTimeout, disconnecting and handing off to the
+// reconnect subsystem. gen is the generation that armed this timeout; if a
+// later reconnect has since superseded it, this is a no-op so a stale timer
+// can't tear down the new connection.
+func (c *Client) onPongTimeout(gen uint64) {
+	if !c.isCurrentGen(gen) {
+		return
+	}
+
+	err := ErrPongTimeout
+	c.sendError(err)
+
+	c.mutex.Lock()
+	c.disconnectErr = err
+	c.mutex.Unlock()
+
+	c.disconnect()
+	c.fireDisconnect(err)
+	c.scheduleReconnect()
+}
+
 func (c *Client) sendError(err error) {
 	select {
 	case c.Errors <- err:
 	default:
 	}
+	c.fireError(err)
 }
 
-func (c *Client) listen() {
-	for c.isConnected() {
-		c.ws.SetReadDeadline(time.Now().Add(c._activityTimeout + pongTimeout))
+// listen runs the read loop for the connection identified by gen, exiting as
+// soon as that connection is no longer the current one so a slow-to-notice
+// stale read loop can't act on a Transport a later reconnect has replaced.
+func (c *Client) listen(gen uint64) {
+	for c.isCurrentGen(gen) {
+		readTimeout := c.PongTimeout
+		if readTimeout <= 0 {
+			readTimeout = pongTimeout
+		}
+		c.Transport.SetReadDeadline(time.Now().Add(c._activityTimeout + readTimeout))
 
 		var event Event
-		err := websocket.JSON.Receive(c.ws, &event)
+		err := c.Transport.ReadJSON(&event)
 		if err != nil {
 			// If the websocket connection was closed, Receive will return an error.
 			// This is expected for an explicit disconnect.
-			if !c.isConnected() {
+			if !c.isCurrentGen(gen) {
 				return
 			}
 			c.sendError(err)
+			c.mutex.Lock()
 			c.disconnectErr = err
-			c.Disconnect()
+			c.mutex.Unlock()
+			c.disconnect()
+			c.fireDisconnect(err)
+			c.scheduleReconnect()
 			break
 		}
 
 		c.resetActivityTimer()
+		// Any inbound frame proves the connection is alive, not just a pong.
+		c.cancelPongTimeout()
 
 		switch event.Event {
 		case pusherPing:
-			websocket.Message.Send(c.ws, pongPayload)
+			c.Transport.WriteJSON(Event{Event: pusherPong, Data: json.RawMessage(`"{}"`)})
 		case pusherPong:
-			// TODO: stop pong timeout timer
+			// Handled above for all frame types; nothing further to do.
 		case pusherError:
-			c.sendError(extractEventError(event))
+			// A pusher:error frame itself carries the disconnect reason, so
+			// drive disconnect/reconnect from the parsed *Error directly
+			// rather than waiting on whatever generic error the socket
+			// produces once Pusher closes it; that's what lets
+			// scheduleReconnect's errors.As(err, &perr) see the real code
+			// and honor classifyCode's 4000-4099 "do not reconnect" rule.
+			perr := extractEventError(event)
+			c.sendError(perr)
+			if e, ok := perr.(*Error); ok && e.Code == 4301 {
+				// A rejected client event leaves the connection usable.
+				break
+			}
+
+			c.mutex.Lock()
+			c.disconnectErr = perr
+			c.mutex.Unlock()
+
+			c.disconnect()
+			c.fireDisconnect(perr)
+			c.scheduleReconnect()
+			return
 		default:
+			// Snapshot everything under RLock and act after releasing it:
+			// enqueue (events.go) takes c.mutex.RLock() itself, so holding
+			// the lock across the call would be a recursive RLock that
+			// deadlocks as soon as a writer (e.g. armPongTimeout) is
+			// waiting, the same hazard fireConnected/fireDisconnect/
+			// fireError already avoid.
 			c.mutex.RLock()
+			boundChans := make([]chan Event, 0, len(c.boundEvents[event.Event]))
 			for boundChan := range c.boundEvents[event.Event] {
+				boundChans = append(boundChans, boundChan)
+			}
+			globalChans := make([]chan Event, 0, len(c.globalBindings))
+			for boundChan := range c.globalBindings {
+				globalChans = append(globalChans, boundChan)
+			}
+			handlers := append([]func(Event){}, c.eventHandlers[event.Event]...)
+			subChan, hasSubChan := c.subscribedChannels[event.Channel]
+			c.mutex.RUnlock()
+
+			for _, boundChan := range boundChans {
+				go func(boundChan chan Event, event Event) {
+					boundChan <- event
+				}(boundChan, event)
+			}
+			for _, boundChan := range globalChans {
 				go func(boundChan chan Event, event Event) {
 					boundChan <- event
 				}(boundChan, event)
 			}
-			if subChan, ok := c.subscribedChannels[event.Channel]; ok {
+			if len(handlers) > 0 {
+				c.enqueue(func() {
+					for _, h := range handlers {
+						h(event)
+					}
+				})
+			}
+			if hasSubChan {
 				subChan.handleEvent(event.Event, event.Data)
 			}
-			c.mutex.RUnlock()
 		}
 	}
 }
@@ -290,10 +515,14 @@ func (c *Client) listen() {
 //
 // See SubscribePresence() for presence channels.
 func (c *Client) Subscribe(channelName string, opts ...SubscribeOption) (Channel, error) {
-	c.mutex.RLock()
+	// Hold a single write lock across the check-and-create so that two
+	// concurrent calls for the same channelName (e.g. from SubscribeContext
+	// racing a caller-abandoned attempt against a fresh one) can't both
+	// observe "not yet subscribed" and create two Channel instances, with the
+	// second clobbering the first's map entry. Both calls still send their own
+	// Subscribe()/auth request on the shared instance after the lock is released.
+	c.mutex.Lock()
 	ch, ok := c.subscribedChannels[channelName]
-	c.mutex.RUnlock()
-
 	if !ok {
 		baseChan := &channel{
 			name:        channelName,
@@ -308,14 +537,43 @@ func (c *Client) Subscribe(channelName string, opts ...SubscribeOption) (Channel
 		default:
 			ch = baseChan
 		}
-		c.mutex.Lock()
 		c.subscribedChannels[channelName] = ch
-		c.mutex.Unlock()
 	}
+	c.mutex.Unlock()
 
 	return ch, ch.Subscribe(opts...)
 }
 
+// SubscribeContext creates a subscription to the specified channel, as
+// Subscribe does, but returns early with ctx.Err() if ctx is done before the
+// subscription completes. It does not cancel the underlying attempt: the
+// Subscribe call (and any private/presence authentication request it makes)
+// keeps running in the background and still takes effect once it finishes,
+// since internalChannel.Subscribe has no context-aware variant to propagate
+// ctx into. Subscribe serializes only the lookup and creation of the Channel
+// for channelName, so an abandoned attempt and a later one for the same
+// channel share one Channel instance; their subscribe requests are still
+// sent independently and may run concurrently.
+func (c *Client) SubscribeContext(ctx context.Context, channelName string, opts ...SubscribeOption) (Channel, error) {
+	type result struct {
+		ch  Channel
+		err error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		ch, err := c.Subscribe(channelName, opts...)
+		done <- result{ch, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-done:
+		return r.ch, r.err
+	}
+}
+
 // SubscribePresence creates a subscription to the specified presence channel.
 // If the channel has already been subscribed, this method will return the
 // existing channel instance.
@@ -386,6 +644,21 @@ func (c *Client) Unbind(event string, chans ...chan Event) {
 
 // SendEvent sends an event on the Pusher connection.
 func (c *Client) SendEvent(event string, data interface{}, channelName string) error {
+	return c.SendEventContext(context.Background(), event, data, channelName)
+}
+
+// SendEventContext sends an event on the Pusher connection. ctx's deadline is
+// propagated to the underlying Transport write, so a write that's still
+// blocked (e.g. on a congested or half-open socket) when ctx expires is
+// aborted rather than sent on the caller's behalf after the fact.
+func (c *Client) SendEventContext(ctx context.Context, event string, data interface{}, channelName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !c.isConnected() {
+		return ErrNotConnected
+	}
+
 	dataJSON, err := json.Marshal(data)
 	if err != nil {
 		return err
@@ -399,16 +672,60 @@ func (c *Client) SendEvent(event string, data interface{}, channelName string) e
 
 	c.resetActivityTimer()
 
-	return websocket.JSON.Send(c.ws, e)
+	return c.Transport.WriteJSONContext(ctx, e)
 }
 
 // Disconnect closes the websocket connection to Pusher. Any subsequent operations
-// are invalid until Connect is called again.
+// are invalid until Connect is called again. Unlike an error detected by the
+// read loop, an explicit Disconnect never triggers the reconnect subsystem.
 func (c *Client) Disconnect() error {
+	return c.DisconnectContext(context.Background())
+}
+
+// DisconnectContext closes the websocket connection to Pusher. ctx's
+// deadline is propagated to the underlying Transport close. Any subsequent
+// operations are invalid until Connect is called again.
+func (c *Client) DisconnectContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.manualDisconnect = true
+	c.mutex.Unlock()
+
+	err := c.disconnectContext(ctx)
+	c.publishState(StateDisconnected)
+	c.fireDisconnect(nil)
+	return err
+}
+
+// disconnect tears down the websocket connection and notifies listeners,
+// without regard for whether the client should subsequently try to
+// reconnect. Callers decide separately whether to call scheduleReconnect.
+func (c *Client) disconnect() error {
+	return c.disconnectContext(context.Background())
+}
+
+// disconnectContext is disconnect with ctx propagated to the underlying
+// Transport close, used by DisconnectContext.
+func (c *Client) disconnectContext(ctx context.Context) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
 	c.connected = false
+	// Stop (rather than wait for) the timers driving this generation's
+	// heartbeat/pong-timeout, and nudge activityTimerReset, since a stopped
+	// timer never delivers on its channel, so a parked heartbeat goroutine
+	// wakes up and exits via its isCurrentGen check promptly.
+	if c.activityTimer != nil {
+		c.activityTimer.Stop()
+	}
+	c.resetActivityTimer()
+	if c.pongTimer != nil {
+		c.pongTimer.Stop()
+		c.pongTimer = nil
+	}
 
 	c.notifyMutex.Lock()
 	defer c.notifyMutex.Unlock()
@@ -424,8 +741,8 @@ func (c *Client) Disconnect() error {
 	}
 	c.closes = c.closes[:0]
 
-	if c.ws != nil {
-		return c.ws.Close()
+	if c.Transport != nil {
+		return c.Transport.CloseContext(ctx)
 	}
 	return nil
 }
@@ -0,0 +1,78 @@
+package pusher
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	clientEventPrefix = "client-"
+	// clientEventMaxRate is Pusher's documented limit of client events per
+	// second, per connection.
+	clientEventMaxRate = 10
+	// clientEventMaxPayload is Pusher's documented payload size limit for a
+	// client event.
+	clientEventMaxPayload = 10 * 1024 // bytes
+)
+
+// Trigger publishes a client event - one whose name is prefixed with
+// "client-" - on the channel, so that other authenticated members receive it
+// directly without a round trip through your server. Client events are only
+// permitted on private and presence channels, and Pusher enforces a limit of
+// clientEventMaxRate events/sec per connection and a clientEventMaxPayload
+// payload size; both are checked here before the event reaches the wire. See
+// https://pusher.com/docs/channels/using_channels/events/#triggering-client-events.
+//
+// Trigger is also declared on the Channel interface (channel.go), so it's
+// reachable through the values Subscribe/SubscribePresence actually return.
+func (ch *channel) Trigger(eventName string, data interface{}) error {
+	if !strings.HasPrefix(eventName, clientEventPrefix) {
+		return fmt.Errorf("pusher: client event name must start with %q: %s", clientEventPrefix, eventName)
+	}
+	if !strings.HasPrefix(ch.name, "private-") && !strings.HasPrefix(ch.name, "presence-") {
+		return fmt.Errorf("pusher: client events may only be sent on private or presence channels: %s", ch.name)
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if len(payload) > clientEventMaxPayload {
+		return fmt.Errorf("pusher: client event payload of %d bytes exceeds the %d byte limit", len(payload), clientEventMaxPayload)
+	}
+
+	if !ch.client.allowClientEvent() {
+		return ErrClientEventRateLimited
+	}
+
+	return ch.client.SendEvent(eventName, data, ch.name)
+}
+
+// allowClientEvent reports whether another client event may be sent right
+// now, enforcing Pusher's per-connection rate limit with a token-bucket
+// limiter refilled at clientEventMaxRate tokens/sec.
+func (c *Client) allowClientEvent() bool {
+	c.eventRateMu.Lock()
+	defer c.eventRateMu.Unlock()
+
+	now := time.Now()
+	if c.eventTokensUpdated.IsZero() {
+		c.eventTokens = clientEventMaxRate
+		c.eventTokensUpdated = now
+	} else {
+		elapsed := now.Sub(c.eventTokensUpdated).Seconds()
+		c.eventTokens += elapsed * clientEventMaxRate
+		if c.eventTokens > clientEventMaxRate {
+			c.eventTokens = clientEventMaxRate
+		}
+		c.eventTokensUpdated = now
+	}
+
+	if c.eventTokens < 1 {
+		return false
+	}
+	c.eventTokens--
+	return true
+}
@@ -0,0 +1,104 @@
+package pusher
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is a structured representation of a pusher:error event, or of an
+// error the client infers on the protocol's behalf (e.g. an unparseable
+// frame). Code follows Pusher's documented ranges; see
+// https://pusher.com/docs/channels/library_auth_reference/pusher-websockets-protocol/#error-codes.
+type Error struct {
+	Code    int
+	Message string
+	// Recoverable reports whether the reconnect subsystem should retry after
+	// this error, rather than giving up permanently.
+	Recoverable bool
+	// ImmediateRetry reports whether the reconnect subsystem should redial
+	// right away instead of waiting out its usual backoff, per Pusher's
+	// documented 4200-4299 "reconnect immediately" range. It is only
+	// meaningful when Recoverable is true.
+	ImmediateRetry bool
+}
+
+func (e *Error) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("pusher: %s", e.Message)
+	}
+	return fmt.Sprintf("pusher: %s (code %d)", e.Message, e.Code)
+}
+
+// newPusherError builds an *Error from a pusher:error event's code and
+// message, classifying it per classifyCode.
+func newPusherError(code int, message string) *Error {
+	recoverable, immediate := classifyCode(code)
+	return &Error{
+		Code:           code,
+		Message:        message,
+		Recoverable:    recoverable,
+		ImmediateRetry: immediate,
+	}
+}
+
+// classifyCode reports how the reconnect subsystem should respond to the
+// given pusher:error code, per Pusher's documented ranges:
+//   - 4000-4099: the connection was closed deliberately and must not be reconnected
+//   - 4100-4199: the connection should be reconnected after waiting >= 1s
+//   - 4200-4299: the connection should be reconnected immediately
+//   - 4301: the client event rate limit was exceeded; the connection itself
+//     is fine and is left alone
+func classifyCode(code int) (recoverable, immediate bool) {
+	switch {
+	case code == 4301:
+		return true, false
+	case code >= 4000 && code <= 4099:
+		return false, false
+	case code >= 4100 && code <= 4199:
+		return true, false
+	case code >= 4200 && code <= 4299:
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+// extractEventError converts a pusher:error event into an *Error.
+func extractEventError(event Event) error {
+	var payload struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := UnmarshalDataString(event.Data, &payload); err != nil {
+		return fmt.Errorf("pusher: received unparseable pusher:error event: %w", err)
+	}
+	return newPusherError(payload.Code, payload.Message)
+}
+
+// Sentinel errors returned by Client and Channel methods. Use errors.Is to
+// check for them.
+var (
+	// ErrNotConnected is returned by methods that require an established
+	// connection, such as SendEvent, when called before Connect or after a
+	// disconnect.
+	ErrNotConnected = errors.New("pusher: not connected")
+
+	// ErrAlreadySubscribed describes subscribing to a channel that is already
+	// subscribed. Client.Subscribe and Client.SubscribePresence do not return
+	// it: for an already-subscribed channel they return the existing Channel.
+	ErrAlreadySubscribed = errors.New("pusher: already subscribed to channel")
+
+	// ErrAuthFailed is returned when a private or presence channel
+	// subscription's auth request fails, returns a non-200 status, or returns
+	// a body that cannot be decoded as an auth response.
+	ErrAuthFailed = errors.New("pusher: channel authentication failed")
+
+	// ErrPongTimeout is returned, and set as the disconnect reason, when the
+	// server fails to respond to a pusher:ping within Client.PongTimeout.
+	ErrPongTimeout = errors.New("pusher: no pong received before timeout, connection presumed dead")
+
+	// ErrClientEventRateLimited is returned by Channel.Trigger when the
+	// client's own token-bucket limiter rejects an event before it reaches
+	// the wire, ahead of Pusher's server-side 4301 rate-limit error.
+	ErrClientEventRateLimited = errors.New("pusher: client event rate limit exceeded")
+)
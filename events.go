@@ -0,0 +1,154 @@
+package pusher
+
+// dispatchQueueSize bounds how many pending handler invocations may queue up
+// before new ones are dropped, so that a slow OnEvent/OnDisconnect/OnError/
+// OnConnected handler cannot block the read loop.
+const dispatchQueueSize = 256
+
+// BindGlobal returns a channel to which every event received on the
+// connection is sent, regardless of its name or channel. Unlike Bind, it is
+// not scoped to a single event name. Like Bind, it does not receive the
+// pusher:ping, pusher:pong and pusher:error frames the client handles itself.
+func (c *Client) BindGlobal() chan Event {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	boundChan := make(chan Event)
+	if c.globalBindings == nil {
+		c.globalBindings = boundEventChans{}
+	}
+	c.globalBindings[boundChan] = struct{}{}
+
+	return boundChan
+}
+
+// UnbindGlobal removes global bindings. If chans are passed, only those
+// bindings are removed. Otherwise, all global bindings are removed.
+func (c *Client) UnbindGlobal(chans ...chan Event) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(chans) == 0 {
+		c.globalBindings = boundEventChans{}
+		return
+	}
+
+	for _, boundChan := range chans {
+		delete(c.globalBindings, boundChan)
+	}
+}
+
+// OnEvent registers handler to be invoked for every event named event,
+// received on any channel. Handlers run from a dedicated dispatch goroutine
+// with a bounded queue, so unlike a channel returned by Bind, a slow handler
+// cannot block the read loop; once the queue is full, further invocations are
+// dropped.
+func (c *Client) OnEvent(event string, handler func(Event)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.ensureDispatchLocked()
+	if c.eventHandlers == nil {
+		c.eventHandlers = map[string][]func(Event){}
+	}
+	c.eventHandlers[event] = append(c.eventHandlers[event], handler)
+}
+
+// OnConnected registers handler to be invoked, from the dispatch goroutine,
+// every time Connect (including an automatic reconnect) succeeds.
+func (c *Client) OnConnected(handler func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.ensureDispatchLocked()
+	c.connectedHandlers = append(c.connectedHandlers, handler)
+}
+
+// OnDisconnect registers handler to be invoked, from the dispatch goroutine,
+// whenever the connection is closed. err is nil for a graceful Disconnect.
+func (c *Client) OnDisconnect(handler func(error)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.ensureDispatchLocked()
+	c.disconnectHandlers = append(c.disconnectHandlers, handler)
+}
+
+// OnError registers handler to be invoked, from the dispatch goroutine,
+// whenever an error would otherwise only be sent to Client.Errors.
+func (c *Client) OnError(handler func(error)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.ensureDispatchLocked()
+	c.errorHandlers = append(c.errorHandlers, handler)
+}
+
+// ensureDispatchLocked lazily starts the dispatch goroutine. Callers must
+// hold c.mutex.
+func (c *Client) ensureDispatchLocked() {
+	if c.dispatchQueue == nil {
+		c.dispatchQueue = make(chan func(), dispatchQueueSize)
+		go c.dispatchLoop()
+	}
+}
+
+func (c *Client) dispatchLoop() {
+	c.mutex.RLock()
+	queue := c.dispatchQueue
+	c.mutex.RUnlock()
+
+	for job := range queue {
+		job()
+	}
+}
+
+// enqueue schedules job to run on the dispatch goroutine, dropping it if the
+// queue is full rather than blocking the caller.
+func (c *Client) enqueue(job func()) {
+	c.mutex.RLock()
+	queue := c.dispatchQueue
+	c.mutex.RUnlock()
+
+	if queue == nil {
+		return
+	}
+
+	select {
+	case queue <- job:
+	default:
+		// Dispatch queue is full; drop rather than block the read loop.
+	}
+}
+
+func (c *Client) fireConnected() {
+	c.mutex.RLock()
+	handlers := append([]func(){}, c.connectedHandlers...)
+	c.mutex.RUnlock()
+
+	for _, h := range handlers {
+		c.enqueue(h)
+	}
+}
+
+func (c *Client) fireDisconnect(err error) {
+	c.mutex.RLock()
+	handlers := append([]func(error){}, c.disconnectHandlers...)
+	c.mutex.RUnlock()
+
+	for _, h := range handlers {
+		h := h
+		c.enqueue(func() { h(err) })
+	}
+}
+
+func (c *Client) fireError(err error) {
+	c.mutex.RLock()
+	handlers := append([]func(error){}, c.errorHandlers...)
+	c.mutex.RUnlock()
+
+	for _, h := range handlers {
+		h := h
+		c.enqueue(func() { h(err) })
+	}
+}
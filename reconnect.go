@@ -0,0 +1,161 @@
+package pusher
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ConnectionState describes a transition in the lifecycle of a Client's
+// connection to Pusher. Clients that set Client.StateChanges receive these
+// as the reconnect subsystem redials after an unexpected disconnect.
+type ConnectionState string
+
+const (
+	StateConnecting   ConnectionState = "connecting"
+	StateConnected    ConnectionState = "connected"
+	StateDisconnected ConnectionState = "disconnected"
+	StateReconnecting ConnectionState = "reconnecting"
+	StateFailed       ConnectionState = "failed"
+)
+
+const (
+	defaultReconnectWait    = 1 * time.Second
+	defaultMaxReconnectWait = 30 * time.Second
+)
+
+// publishState sends state on StateChanges without blocking if nobody is
+// listening, or if the caller hasn't opted in by setting the channel.
+func (c *Client) publishState(state ConnectionState) {
+	if c.StateChanges == nil {
+		return
+	}
+	select {
+	case c.StateChanges <- state:
+	default:
+	}
+}
+
+// scheduleReconnect starts the reconnect subsystem in the background, unless
+// the disconnect was caused by an explicit call to Disconnect, or the
+// disconnect reason is a *pusher.Error in a non-recoverable code range (see
+// classifyCode). If the error is in Pusher's "reconnect immediately"
+// 4200-4299 range, the first redial attempt skips its usual backoff; if it is
+// in the 4100-4199 range, redialing starts no sooner than 1s after this call.
+func (c *Client) scheduleReconnect() {
+	c.mutex.RLock()
+	manual := c.manualDisconnect
+	err := c.disconnectErr
+	c.mutex.RUnlock()
+
+	if manual {
+		c.publishState(StateDisconnected)
+		return
+	}
+
+	var perr *Error
+	immediate := false
+	var minWait time.Duration
+	if errors.As(err, &perr) {
+		if !perr.Recoverable {
+			c.publishState(StateFailed)
+			return
+		}
+		immediate = perr.ImmediateRetry
+		if perr.Code >= 4100 && perr.Code <= 4199 {
+			minWait = time.Second
+		}
+	}
+
+	go func() {
+		time.Sleep(minWait)
+		c.reconnect(immediate)
+	}()
+}
+
+// reconnectBackoff computes a full-jitter exponential backoff delay for the
+// given attempt number (0-indexed), in the style of go-redis PubSub and NATS
+// reconnect loops: sleep = rand(0, min(MaxReconnectWait, ReconnectWait*2^attempt)) + rand(0, ReconnectJitter).
+func (c *Client) reconnectBackoff(attempt int) time.Duration {
+	wait := c.ReconnectWait
+	if wait <= 0 {
+		wait = defaultReconnectWait
+	}
+	maxWait := c.MaxReconnectWait
+	if maxWait <= 0 {
+		maxWait = defaultMaxReconnectWait
+	}
+
+	backoff := wait * time.Duration(uint64(1)<<uint(attempt))
+	if backoff <= 0 || backoff > maxWait {
+		backoff = maxWait
+	}
+
+	sleep := time.Duration(rand.Int63n(int64(backoff) + 1))
+	if c.ReconnectJitter > 0 {
+		sleep += time.Duration(rand.Int63n(int64(c.ReconnectJitter) + 1))
+	}
+	return sleep
+}
+
+// reconnect redials Pusher after an unexpected disconnect, backing off
+// between attempts, and re-subscribes every previously subscribed channel so
+// that bindings and presence member state are rebuilt from fresh
+// pusher_internal:subscription_succeeded payloads. If immediate is true, the
+// first attempt skips its backoff and redials right away, per Pusher's
+// documented 4200-4299 error range; later attempts back off normally.
+func (c *Client) reconnect(immediate bool) {
+	c.publishState(StateReconnecting)
+
+	for attempt := 0; c.MaxReconnectAttempts <= 0 || attempt < c.MaxReconnectAttempts; attempt++ {
+		c.mutex.RLock()
+		manual := c.manualDisconnect
+		appKey := c.appKey
+		c.mutex.RUnlock()
+		if manual {
+			return
+		}
+
+		wait := c.reconnectBackoff(attempt)
+		if attempt == 0 && immediate {
+			wait = 0
+		}
+		time.Sleep(wait)
+
+		// An explicit Disconnect may have arrived while this attempt was
+		// sleeping through its backoff; re-check before redialing so it
+		// actually stops the reconnect subsystem as Disconnect's doc
+		// comment promises, rather than only taking effect on the next loop
+		// iteration's pre-sleep check.
+		c.mutex.RLock()
+		manual = c.manualDisconnect
+		c.mutex.RUnlock()
+		if manual {
+			return
+		}
+
+		c.publishState(StateConnecting)
+		if err := c.Connect(appKey); err != nil {
+			c.sendError(err)
+			c.publishState(StateReconnecting)
+			continue
+		}
+
+		c.mutex.RLock()
+		channels := make([]internalChannel, 0, len(c.subscribedChannels))
+		for _, ch := range c.subscribedChannels {
+			channels = append(channels, ch)
+		}
+		c.mutex.RUnlock()
+
+		for _, ch := range channels {
+			if err := ch.Subscribe(); err != nil {
+				c.sendError(err)
+			}
+		}
+
+		return
+	}
+
+	c.publishState(StateFailed)
+}
@@ -0,0 +1,94 @@
+package pusher
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transport abstracts the WebSocket connection used by Client, decoupling it
+// from any single implementation. The default, used when Client.Transport is
+// left nil, is backed by github.com/gorilla/websocket. Implement this
+// interface to inject a custom dialer, proxy, or TLS configuration.
+type Transport interface {
+	// DialContext establishes the connection to urlStr, sending header during
+	// the handshake, and aborts if ctx is done first.
+	DialContext(ctx context.Context, urlStr string, header http.Header) error
+	// ReadJSON blocks until the next frame arrives and unmarshals it into v.
+	ReadJSON(v interface{}) error
+	// WriteJSON marshals v and sends it as a single frame.
+	WriteJSON(v interface{}) error
+	// WriteJSONContext marshals v and sends it as a single frame, honoring
+	// ctx's deadline on the write (gorilla/websocket has no native context
+	// parameter for writes, so this is implemented via SetWriteDeadline).
+	WriteJSONContext(ctx context.Context, v interface{}) error
+	// SetReadDeadline arranges for ReadJSON to fail with a timeout error after t.
+	SetReadDeadline(t time.Time) error
+	Close() error
+	// CloseContext closes the connection, first returning ctx.Err() without
+	// closing if ctx is already done. The default gorilla/websocket Transport
+	// closes the underlying connection without a close handshake.
+	CloseContext(ctx context.Context) error
+}
+
+// gorillaTransport is the default Transport, backed by gorilla/websocket.
+type gorillaTransport struct {
+	conn *websocket.Conn
+}
+
+func (t *gorillaTransport) DialContext(ctx context.Context, urlStr string, header http.Header) error {
+	dialer := websocket.Dialer{}
+	conn, _, err := dialer.DialContext(ctx, urlStr, header)
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+	return nil
+}
+
+func (t *gorillaTransport) ReadJSON(v interface{}) error {
+	return t.conn.ReadJSON(v)
+}
+
+func (t *gorillaTransport) WriteJSON(v interface{}) error {
+	return t.conn.WriteJSON(v)
+}
+
+func (t *gorillaTransport) WriteJSONContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		t.conn.SetWriteDeadline(dl)
+	} else {
+		t.conn.SetWriteDeadline(time.Time{})
+	}
+	defer t.conn.SetWriteDeadline(time.Time{})
+	return t.conn.WriteJSON(v)
+}
+
+func (t *gorillaTransport) SetReadDeadline(deadline time.Time) error {
+	return t.conn.SetReadDeadline(deadline)
+}
+
+func (t *gorillaTransport) Close() error {
+	if t.conn == nil {
+		return nil
+	}
+	return t.conn.Close()
+}
+
+func (t *gorillaTransport) CloseContext(ctx context.Context) error {
+	if t.conn == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		t.conn.SetWriteDeadline(dl)
+	}
+	return t.conn.Close()
+}